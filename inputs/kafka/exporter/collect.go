@@ -0,0 +1,224 @@
+package exporter
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func (e *Exporter) collect(ch chan<- prometheus.Metric) {
+	if e.franzClient != nil {
+		e.collectFranz(ch)
+		return
+	}
+	e.collectSarama(ch)
+}
+
+func (e *Exporter) collectSarama(ch chan<- prometheus.Metric) {
+	var wg = sync.WaitGroup{}
+	ch <- prometheus.MustNewConstMetric(clusterBrokers, prometheus.GaugeValue, float64(len(e.client.Brokers())))
+
+	offset := make(map[string]map[int32]int64)
+
+	topics, partitionsByTopic, err := e.topicsAndPartitions()
+	if err != nil {
+		level.Error(e.logger).Log("msg", "cannot get topics", "err", err)
+		return
+	}
+
+	getTopicMetrics := func(topic string) {
+		defer wg.Done()
+
+		if !e.wantTopic(topic) {
+			return
+		}
+
+		partitions, ok := partitionsByTopic[topic]
+		if !ok {
+			level.Error(e.logger).Log("msg", "cannot get partitions of topic", "topic", topic)
+			return
+		}
+		if e.kafkaOpts.CollectTopicPartitionMetrics {
+			ch <- prometheus.MustNewConstMetric(topicPartitions, prometheus.GaugeValue, float64(len(partitions)), topic)
+		}
+
+		e.mu.Lock()
+		offset[topic] = make(map[int32]int64, len(partitions))
+		e.mu.Unlock()
+
+		for _, partition := range partitions {
+			broker, err := e.client.Leader(topic, partition)
+			if err != nil {
+				level.Error(e.logger).Log("msg", "cannot get leader of topic partition", "topic", topic, "partition", partition, "err", err)
+			} else if e.kafkaOpts.CollectBrokerTopicMetrics {
+				ch <- prometheus.MustNewConstMetric(topicPartitionLeader, prometheus.GaugeValue, float64(broker.ID()), topic, strconv.FormatInt(int64(partition), 10))
+			}
+
+			currentOffset, err := e.client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				level.Error(e.logger).Log("msg", "cannot get current offset of topic partition", "topic", topic, "partition", partition, "err", err)
+				if isUnknownTopicOrPartition(err) {
+					if _, _, refreshErr := e.forceMetadataRefresh(); refreshErr != nil {
+						level.Error(e.logger).Log("msg", "forced metadata refresh failed", "topic", topic, "err", refreshErr)
+					}
+				}
+			} else {
+				e.mu.Lock()
+				offset[topic][partition] = currentOffset
+				e.mu.Unlock()
+				if e.kafkaOpts.CollectTopicPartitionMetrics {
+					ch <- prometheus.MustNewConstMetric(topicCurrentOffset, prometheus.GaugeValue, float64(currentOffset), topic, strconv.FormatInt(int64(partition), 10))
+				}
+				if e.kafkaOpts.OffsetInterpolation != "offset" {
+					e.recordOffset(topic, partition, currentOffset, time.Now())
+				}
+			}
+
+			if !e.kafkaOpts.CollectTopicPartitionMetrics && !e.kafkaOpts.CollectBrokerTopicMetrics {
+				continue
+			}
+
+			oldestOffset, err := e.client.GetOffset(topic, partition, sarama.OffsetOldest)
+			if err != nil {
+				level.Error(e.logger).Log("msg", "cannot get oldest offset of topic partition", "topic", topic, "partition", partition, "err", err)
+			} else if e.kafkaOpts.CollectTopicPartitionMetrics {
+				ch <- prometheus.MustNewConstMetric(topicOldestOffset, prometheus.GaugeValue, float64(oldestOffset), topic, strconv.FormatInt(int64(partition), 10))
+			}
+
+			if !e.kafkaOpts.CollectBrokerTopicMetrics {
+				continue
+			}
+
+			replicas, err := e.client.Replicas(topic, partition)
+			if err != nil {
+				level.Error(e.logger).Log("msg", "cannot get replicas of topic partition", "topic", topic, "partition", partition, "err", err)
+			} else {
+				ch <- prometheus.MustNewConstMetric(topicPartitionReplicas, prometheus.GaugeValue, float64(len(replicas)), topic, strconv.FormatInt(int64(partition), 10))
+			}
+
+			inSyncReplicas, err := e.client.InSyncReplicas(topic, partition)
+			if err != nil {
+				level.Error(e.logger).Log("msg", "cannot get in-sync replicas of topic partition", "topic", topic, "partition", partition, "err", err)
+			} else {
+				ch <- prometheus.MustNewConstMetric(topicPartitionInSyncReplicas, prometheus.GaugeValue, float64(len(inSyncReplicas)), topic, strconv.FormatInt(int64(partition), 10))
+			}
+
+			preferred := float64(0)
+			if broker != nil && len(replicas) > 0 && broker.ID() == replicas[0] {
+				preferred = 1
+			}
+			ch <- prometheus.MustNewConstMetric(topicPartitionUsesPreferredReplica, prometheus.GaugeValue, preferred, topic, strconv.FormatInt(int64(partition), 10))
+
+			underReplicated := float64(0)
+			if len(inSyncReplicas) < len(replicas) {
+				underReplicated = 1
+			}
+			ch <- prometheus.MustNewConstMetric(topicUnderReplicatedPartition, prometheus.GaugeValue, underReplicated, topic, strconv.FormatInt(int64(partition), 10))
+		}
+	}
+
+	for _, topic := range topics {
+		wg.Add(1)
+		go getTopicMetrics(topic)
+	}
+	wg.Wait()
+
+	e.collectConsumerGroups(ch, offset)
+}
+
+// collectConsumerGroups walks every consumer group known to the cluster
+// and emits current-offset and lag metrics relative to the per-partition
+// offsets gathered in collect().
+func (e *Exporter) collectConsumerGroups(ch chan<- prometheus.Metric, offset map[string]map[int32]int64) {
+	if !e.kafkaOpts.CollectConsumerGroupMetrics {
+		return
+	}
+
+	groups, err := e.listGroups()
+	if err != nil {
+		level.Error(e.logger).Log("msg", "cannot get consumer groups", "err", err)
+		return
+	}
+
+	for _, group := range groups {
+		if !e.wantGroup(group) {
+			continue
+		}
+
+		offsetManager, err := sarama.NewOffsetManagerFromClient(group, e.client)
+		if err != nil {
+			level.Error(e.logger).Log("msg", "cannot get offset manager for group", "group", group, "err", err)
+			continue
+		}
+		defer offsetManager.Close()
+
+		for topic, partitionOffsets := range offset {
+			if !e.wantTopic(topic) {
+				continue
+			}
+
+			var currentOffsetSum, lagSum int64
+			for partition, brokerOffset := range partitionOffsets {
+				pom, err := offsetManager.ManagePartition(topic, partition)
+				if err != nil {
+					continue
+				}
+				groupOffset, _ := pom.NextOffset()
+				pom.AsyncClose()
+
+				if groupOffset < 0 {
+					continue
+				}
+
+				currentOffsetSum += groupOffset
+				lag := brokerOffset - groupOffset
+				if lag < 0 {
+					lag = 0
+				}
+				lagSum += lag
+
+				ch <- prometheus.MustNewConstMetric(consumergroupCurrentOffset, prometheus.GaugeValue, float64(groupOffset), group, topic, strconv.FormatInt(int64(partition), 10))
+				ch <- prometheus.MustNewConstMetric(consumergroupLag, prometheus.GaugeValue, float64(lag), group, topic, strconv.FormatInt(int64(partition), 10))
+
+				if e.kafkaOpts.OffsetInterpolation != "offset" {
+					if seconds, ok := e.lagInSeconds(topic, partition, groupOffset); ok {
+						ch <- prometheus.MustNewConstMetric(consumergroupLagSeconds, prometheus.GaugeValue, seconds, group, topic, strconv.FormatInt(int64(partition), 10))
+					}
+				}
+			}
+
+			ch <- prometheus.MustNewConstMetric(consumergroupCurrentOffsetSum, prometheus.GaugeValue, float64(currentOffsetSum), group, topic)
+			ch <- prometheus.MustNewConstMetric(consumergroupLagSum, prometheus.GaugeValue, float64(lagSum), group, topic)
+		}
+	}
+}
+
+func (e *Exporter) listGroups() ([]string, error) {
+	broker := e.client.Brokers()
+	if len(broker) == 0 {
+		return nil, nil
+	}
+
+	controller, err := e.client.Controller()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := controller.ListGroups(&sarama.ListGroupsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Err != sarama.ErrNoError {
+		return nil, resp.Err
+	}
+
+	groups := make([]string, 0, len(resp.Groups))
+	for group := range resp.Groups {
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
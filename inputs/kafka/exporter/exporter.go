@@ -0,0 +1,384 @@
+package exporter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const (
+	namespace = "kafka"
+	clientID  = "categraf"
+)
+
+var (
+	clusterBrokers                     = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "brokers"), "Number of Brokers in the Kafka Cluster.", nil, nil)
+	topicPartitions                    = prometheus.NewDesc(prometheus.BuildFQName(namespace, "topic", "partitions"), "Number of partitions for this Topic", []string{"topic"}, nil)
+	topicCurrentOffset                 = prometheus.NewDesc(prometheus.BuildFQName(namespace, "topic", "partition_current_offset"), "Current Offset of a Broker at Topic/Partition", []string{"topic", "partition"}, nil)
+	topicOldestOffset                  = prometheus.NewDesc(prometheus.BuildFQName(namespace, "topic", "partition_oldest_offset"), "Oldest Offset of a Broker at Topic/Partition", []string{"topic", "partition"}, nil)
+	topicPartitionLeader               = prometheus.NewDesc(prometheus.BuildFQName(namespace, "topic", "partition_leader"), "Leader Broker ID of this Topic/Partition", []string{"topic", "partition"}, nil)
+	topicPartitionReplicas             = prometheus.NewDesc(prometheus.BuildFQName(namespace, "topic", "partition_replicas"), "Number of Replicas for this Topic/Partition", []string{"topic", "partition"}, nil)
+	topicPartitionInSyncReplicas       = prometheus.NewDesc(prometheus.BuildFQName(namespace, "topic", "partition_in_sync_replica"), "Number of In-Sync Replicas for this Topic/Partition", []string{"topic", "partition"}, nil)
+	topicPartitionUsesPreferredReplica = prometheus.NewDesc(prometheus.BuildFQName(namespace, "topic", "partition_leader_is_preferred"), "1 if Topic/Partition is using the Preferred Broker", []string{"topic", "partition"}, nil)
+	topicUnderReplicatedPartition      = prometheus.NewDesc(prometheus.BuildFQName(namespace, "topic", "partition_under_replicated_partition"), "1 if Topic/Partition is under Replicated", []string{"topic", "partition"}, nil)
+	consumergroupCurrentOffset         = prometheus.NewDesc(prometheus.BuildFQName(namespace, "consumergroup", "current_offset"), "Current Offset of a ConsumerGroup at Topic/Partition", []string{"consumergroup", "topic", "partition"}, nil)
+	consumergroupCurrentOffsetSum      = prometheus.NewDesc(prometheus.BuildFQName(namespace, "consumergroup", "current_offset_sum"), "Current Offset of a ConsumerGroup at Topic for all partitions", []string{"consumergroup", "topic"}, nil)
+	consumergroupLag                   = prometheus.NewDesc(prometheus.BuildFQName(namespace, "consumergroup", "lag"), "Current Approximate Lag of a ConsumerGroup at Topic/Partition", []string{"consumergroup", "topic", "partition"}, nil)
+	consumergroupLagSum                = prometheus.NewDesc(prometheus.BuildFQName(namespace, "consumergroup", "lag_sum"), "Current Approximate Lag of a ConsumerGroup at Topic for all partitions", []string{"consumergroup", "topic"}, nil)
+	consumergroupLagZookeeper          = prometheus.NewDesc(prometheus.BuildFQName(namespace, "consumergroup", "lag_zookeeper"), "Current Approximate Lag(zookeeper) of a ConsumerGroup at Topic/Partition", []string{"consumergroup", "topic", "partition"}, nil)
+	consumergroupLagSeconds            = prometheus.NewDesc(prometheus.BuildFQName(namespace, "consumergroup", "lag_seconds"), "Interpolated time in seconds a ConsumerGroup is behind the head of a Topic/Partition. Only emitted when offset_interpolation is \"interpolation\".", []string{"consumergroup", "topic", "partition"}, nil)
+	consumergroupMembers               = prometheus.NewDesc(prometheus.BuildFQName(namespace, "consumergroup", "members"), "Amount of members in a consumer group", []string{"consumergroup"}, nil)
+)
+
+// Options holds all the knobs used to build a sarama/kafka client and
+// drive the metric collection behaviour of an Exporter instance.
+type Options struct {
+	Uri                      []string
+	UseSASL                  bool
+	UseSASLHandshake         bool
+	SaslUsername             string
+	SaslPassword             string
+	SaslMechanism            string
+	UseTLS                   bool
+	TlsServerName            string
+	TlsCAFile                string
+	TlsCertFile              string
+	TlsKeyFile               string
+	TlsInsecureSkipTLSVerify bool
+	TlsMinVersion            string
+	TlsCipherSuites          []string
+	KafkaVersion             string
+	UseZooKeeperLag          bool
+	UriZookeeper             []string
+	Labels                   string
+	MetadataRefreshInterval  string
+	AllowConcurrent          bool
+	MaxOffsets               int
+	PruneIntervalSeconds     int
+
+	// SASL/OAUTHBEARER (SASL OAuth2 / OIDC) options. Only used when
+	// SaslMechanism is "oauthbearer".
+	OAuthTokenURL     string
+	OAuthClientID     string
+	OAuthClientSecret string
+	OAuthScopes       []string
+	OAuthExtensions   map[string]string
+	OAuthTokenFile    string
+
+	// Cardinality controls. TopicExcludeFilter/GroupExcludeFilter are
+	// applied after the topicFilter/groupFilter passed to New and let
+	// operators carve out noisy topics/groups without rewriting the main
+	// filter. The Collect* toggles drop whole metric families.
+	TopicExcludeFilter           string
+	GroupExcludeFilter           string
+	CollectTopicPartitionMetrics bool
+	CollectConsumerGroupMetrics  bool
+	CollectBrokerTopicMetrics    bool
+
+	// OffsetInterpolation selects how consumer group lag is computed:
+	// "interpolation" (default) or "offset". Both emit the offset-difference
+	// lag; "interpolation" also records per-partition offset samples and
+	// emits the lag_seconds estimate from them.
+	OffsetInterpolation string
+
+	// MetadataMinInterval is the minimum time between Metadata RPCs issued
+	// to refresh the cached topic/partition list. See metadata.go.
+	MetadataMinInterval string
+
+	// Client selects the Kafka client backend: "sarama" (default) or
+	// "franz" (github.com/twmb/franz-go). See franz.go.
+	Client string
+
+	// AWS_MSK_IAM SigV4 auth, only used when Client is "franz" and
+	// SaslMechanism is "aws_msk_iam".
+	AWSRegion   string
+	AWSProfile  string
+	AWSRoleARN  string
+	AWSEndpoint string
+}
+
+// Exporter collects Kafka stats from the given server and exports them
+// using the prometheus metrics package.
+type Exporter struct {
+	client      sarama.Client
+	franzClient *kgo.Client
+	kadmClient  *kadm.Client
+
+	topicFilter           *regexp.Regexp
+	topicExcludeFilter    *regexp.Regexp
+	groupFilter           *regexp.Regexp
+	groupExcludeFilter    *regexp.Regexp
+	mu                    sync.Mutex
+	useZooKeeperLag       bool
+	allowConcurrent       bool
+	sgMutex               sync.Mutex
+	sgWaitCh              chan struct{}
+	sgChans               []chan<- prometheus.Metric
+	consumerGroupFetchAll bool
+	kafkaOpts             Options
+	saramaConfig          *sarama.Config
+	logger                log.Logger
+	certReloader          CertReloader
+
+	interpolationMap map[string]*interpolationEntry
+
+	metadataMinInterval time.Duration
+	metadataMu          sync.Mutex
+	metadataRefreshedAt time.Time
+	cachedTopics        []string
+	cachedPartitions    map[string][]int32
+	cachedFranzMetadata kadm.Metadata
+}
+
+// wantTopic reports whether topic should be collected, applying the
+// include filter followed by the (optional) exclude filter.
+func (e *Exporter) wantTopic(topic string) bool {
+	if !e.topicFilter.MatchString(topic) {
+		return false
+	}
+	if e.topicExcludeFilter != nil && e.topicExcludeFilter.MatchString(topic) {
+		return false
+	}
+	return true
+}
+
+// wantGroup reports whether group should be collected, applying the
+// include filter followed by the (optional) exclude filter.
+func (e *Exporter) wantGroup(group string) bool {
+	if !e.groupFilter.MatchString(group) {
+		return false
+	}
+	if e.groupExcludeFilter != nil && e.groupExcludeFilter.MatchString(group) {
+		return false
+	}
+	return true
+}
+
+type interpolationEntry struct {
+	offsets []offsetEntry
+}
+
+type offsetEntry struct {
+	offset    int64
+	timestamp time.Time
+}
+
+// New creates a new Exporter for the given options, restricting the
+// collected topics and groups to those matching topicFilter/groupFilter.
+// opts.Client selects the underlying Kafka client backend; both backends
+// produce the same metric series.
+func New(logger log.Logger, opts Options, topicFilter, groupFilter string) (*Exporter, error) {
+	var err error
+
+	metadataMinInterval := 10 * time.Minute
+	if opts.MetadataMinInterval != "" {
+		metadataMinInterval, err = time.ParseDuration(opts.MetadataMinInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata_min_interval: %w", err)
+		}
+	}
+
+	var topicExclude, groupExclude *regexp.Regexp
+	if opts.TopicExcludeFilter != "" {
+		topicExclude, err = regexp.Compile(opts.TopicExcludeFilter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid topic_exclude_regex: %w", err)
+		}
+	}
+	if opts.GroupExcludeFilter != "" {
+		groupExclude, err = regexp.Compile(opts.GroupExcludeFilter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group_exclude_regex: %w", err)
+		}
+	}
+
+	e := &Exporter{
+		topicFilter:           regexp.MustCompile(topicFilter),
+		topicExcludeFilter:    topicExclude,
+		groupFilter:           regexp.MustCompile(groupFilter),
+		groupExcludeFilter:    groupExclude,
+		useZooKeeperLag:       opts.UseZooKeeperLag,
+		allowConcurrent:       opts.AllowConcurrent,
+		sgMutex:               sync.Mutex{},
+		consumerGroupFetchAll: true,
+		kafkaOpts:             opts,
+		logger:                logger,
+		interpolationMap:      make(map[string]*interpolationEntry),
+		metadataMinInterval:   metadataMinInterval,
+		cachedPartitions:      make(map[string][]int32),
+	}
+
+	switch strings.ToLower(opts.Client) {
+	case "", "sarama":
+		if err := e.initSarama(opts); err != nil {
+			return nil, err
+		}
+	case "franz":
+		if err := e.initFranz(opts); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid client %q: can only be sarama or franz", opts.Client)
+	}
+
+	level.Info(logger).Log("msg", "connected to kafka cluster", "brokers", strings.Join(opts.Uri, ","), "client", opts.Client)
+
+	return e, nil
+}
+
+func (e *Exporter) initSarama(opts Options) error {
+	config := sarama.NewConfig()
+	config.ClientID = clientID
+	kafkaVersion, err := sarama.ParseKafkaVersion(opts.KafkaVersion)
+	if err != nil {
+		return err
+	}
+	config.Version = kafkaVersion
+
+	if err := ConfigureSASL(config, opts); err != nil {
+		return err
+	}
+
+	if opts.UseTLS {
+		reloader, err := ConfigureTLS(config, opts)
+		if err != nil {
+			return err
+		}
+		e.certReloader = reloader
+	}
+
+	client, err := sarama.NewClient(opts.Uri, config)
+	if err != nil {
+		return fmt.Errorf("error initializing kafka client: %w", err)
+	}
+
+	e.client = client
+	e.saramaConfig = config
+	return nil
+}
+
+// Close shuts down the underlying Kafka client and stops any background
+// cert-reload goroutine started for it.
+func (e *Exporter) Close() {
+	if e.client != nil {
+		e.client.Close()
+	}
+	if e.franzClient != nil {
+		e.franzClient.Close()
+	}
+	if e.certReloader != nil {
+		e.certReloader.Stop()
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clusterBrokers
+	ch <- topicPartitions
+	ch <- topicCurrentOffset
+	ch <- topicOldestOffset
+	ch <- topicPartitionLeader
+	ch <- topicPartitionReplicas
+	ch <- topicPartitionInSyncReplicas
+	ch <- topicPartitionUsesPreferredReplica
+	ch <- topicUnderReplicatedPartition
+	ch <- consumergroupCurrentOffset
+	ch <- consumergroupCurrentOffsetSum
+	ch <- consumergroupLag
+	ch <- consumergroupLagSum
+	ch <- consumergroupLagZookeeper
+	ch <- consumergroupLagSeconds
+	ch <- consumergroupMembers
+}
+
+// Collect implements prometheus.Collector.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	if e.allowConcurrent {
+		e.collect(ch)
+		return
+	}
+
+	// Locking to avoid race add
+	e.sgMutex.Lock()
+	e.sgChans = append(e.sgChans, ch)
+	// Already running, will loop over the channel
+	if len(e.sgChans) > 1 {
+		e.sgMutex.Unlock()
+		return
+	}
+	e.sgMutex.Unlock()
+
+	e.collect(ch)
+
+	e.sgMutex.Lock()
+	for _, c := range e.sgChans[1:] {
+		close(c)
+	}
+	e.sgChans = nil
+	e.sgMutex.Unlock()
+}
+
+// ConfigureTLS builds config's TLS settings from opts. When opts enables a
+// client cert/key pair, it starts a background certReloader goroutine and
+// returns it so the caller can Stop it once config is no longer in use;
+// the returned reloader is nil whenever no cert/key pair was configured.
+func ConfigureTLS(config *sarama.Config, opts Options) (CertReloader, error) {
+	config.Net.TLS.Enable = true
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: opts.TlsInsecureSkipTLSVerify,
+		ServerName:         opts.TlsServerName,
+	}
+
+	minVersion, err := tlsMinVersion(opts.TlsMinVersion)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.MinVersion = minVersion
+
+	if len(opts.TlsCipherSuites) > 0 {
+		suites, err := tlsCipherSuites(opts.TlsCipherSuites)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.CipherSuites = suites
+	}
+
+	if opts.TlsCAFile != "" {
+		ca, err := ioutil.ReadFile(opts.TlsCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ca file: %w", err)
+		}
+		certPool := x509.NewCertPool()
+		certPool.AppendCertsFromPEM(ca)
+		tlsConfig.RootCAs = certPool
+	}
+
+	// Returned as the CertReloader interface rather than the concrete
+	// *certReloader so that, left unset, it's a true nil interface value
+	// (a nil *certReloader boxed into the interface would not be).
+	var reloader CertReloader
+	if opts.TlsCertFile != "" && opts.TlsKeyFile != "" {
+		r, err := newCertReloader(opts.TlsCertFile, opts.TlsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading client cert/key pair: %w", err)
+		}
+		tlsConfig.GetClientCertificate = r.GetClientCertificate
+		reloader = r
+	}
+
+	config.Net.TLS.Config = tlsConfig
+	return reloader, nil
+}
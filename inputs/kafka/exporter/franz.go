@@ -0,0 +1,164 @@
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	kaws "github.com/twmb/franz-go/pkg/sasl/aws"
+	"github.com/twmb/franz-go/pkg/sasl/oauth"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// GetKafkaClientOptions maps an Options value onto the kgo.Opt slice used
+// to build a franz-go client (broker list, TLS, SASL mechanism including
+// AWS_MSK_IAM), so the franz and sarama backends are configured from the
+// same knobs. TLS here applies only TlsInsecureSkipTLSVerify and
+// TlsServerName: the CA file, client cert/key, minimum version and cipher
+// suites that ConfigureTLS applies for sarama are not used by this backend.
+func GetKafkaClientOptions(opts Options) ([]kgo.Opt, error) {
+	return getFranzOpts(opts)
+}
+
+func (e *Exporter) initFranz(opts Options) error {
+	kOpts, err := getFranzOpts(opts)
+	if err != nil {
+		return err
+	}
+
+	client, err := kgo.NewClient(kOpts...)
+	if err != nil {
+		return fmt.Errorf("error initializing franz-go kafka client: %w", err)
+	}
+
+	if err := client.Ping(context.Background()); err != nil {
+		client.Close()
+		return fmt.Errorf("error connecting to kafka cluster: %w", err)
+	}
+
+	e.franzClient = client
+	e.kadmClient = kadm.NewClient(client)
+	return nil
+}
+
+// getFranzOpts is the real implementation behind GetKafkaClientOptions; it
+// is split out so initFranz can reuse it without re-deriving the SASL
+// mechanism twice.
+func getFranzOpts(opts Options) ([]kgo.Opt, error) {
+	kOpts := []kgo.Opt{
+		kgo.SeedBrokers(opts.Uri...),
+		kgo.ClientID(clientID),
+	}
+
+	if opts.UseTLS {
+		tlsConfig := &tls.Config{
+			InsecureSkipVerify: opts.TlsInsecureSkipTLSVerify,
+			ServerName:         opts.TlsServerName,
+		}
+		kOpts = append(kOpts, kgo.DialTLSConfig(tlsConfig))
+	}
+
+	if !opts.UseSASL {
+		return kOpts, nil
+	}
+
+	switch strings.ToLower(opts.SaslMechanism) {
+	case "plain", "":
+		kOpts = append(kOpts, kgo.SASL(plain.Auth{User: opts.SaslUsername, Pass: opts.SaslPassword}.AsMechanism()))
+	case "scram-sha256":
+		kOpts = append(kOpts, kgo.SASL(scram.Auth{User: opts.SaslUsername, Pass: opts.SaslPassword}.AsSha256Mechanism()))
+	case "scram-sha512":
+		kOpts = append(kOpts, kgo.SASL(scram.Auth{User: opts.SaslUsername, Pass: opts.SaslPassword}.AsSha512Mechanism()))
+	case "oauthbearer":
+		provider, err := newOAuthTokenProvider(opts)
+		if err != nil {
+			return nil, err
+		}
+		kOpts = append(kOpts, kgo.SASL(oauth.Oauth(func(ctx context.Context) (oauth.Auth, error) {
+			tok, err := provider.Token()
+			if err != nil {
+				return oauth.Auth{}, err
+			}
+			return oauth.Auth{Token: tok.Token}, nil
+		})))
+	case "aws_msk_iam":
+		creds, err := awsMSKIAMCredentials(opts)
+		if err != nil {
+			return nil, err
+		}
+		kOpts = append(kOpts, kgo.SASL(kaws.ManagedStreamingIAM(func(ctx context.Context) (kaws.Auth, error) {
+			return awsMSKIAMAuth(ctx, creds)
+		})))
+	default:
+		return nil, fmt.Errorf("invalid sasl mechanism %q for client=franz: can only be plain, scram-sha256, scram-sha512, oauthbearer or aws_msk_iam", opts.SaslMechanism)
+	}
+
+	return kOpts, nil
+}
+
+// awsMSKIAMCredentials resolves, once at client-build time, the credentials
+// provider used to sign each MSK auth request with SigV4 (assuming
+// AWSRoleARN via STS when provided, and resolving STS/the credential chain
+// against AWSEndpoint when set, e.g. for a VPC or FIPS STS endpoint). It's
+// wrapped in an aws.CredentialsCache so repeated Retrieve calls reuse a
+// cached credential set and only hit STS/the credential chain again once
+// it's within its own expiry window, rather than signing every connection
+// with a long-stale snapshot.
+func awsMSKIAMCredentials(opts Options) (aws.CredentialsProvider, error) {
+	ctx := context.Background()
+
+	var optFns []func(*awsconfig.LoadOptions) error
+	if opts.AWSRegion != "" {
+		optFns = append(optFns, awsconfig.WithRegion(opts.AWSRegion))
+	}
+	if opts.AWSProfile != "" {
+		optFns = append(optFns, awsconfig.WithSharedConfigProfile(opts.AWSProfile))
+	}
+	if opts.AWSEndpoint != "" {
+		resolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, args ...interface{}) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: opts.AWSEndpoint, SigningRegion: opts.AWSRegion}, nil
+		})
+		optFns = append(optFns, awsconfig.WithEndpointResolverWithOptions(resolver))
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("could not load aws config for aws_msk_iam: %w", err)
+	}
+
+	provider := cfg.Credentials
+	if opts.AWSRoleARN != "" {
+		stsClient := sts.NewFromConfig(cfg)
+		provider = stscreds.NewAssumeRoleProvider(stsClient, opts.AWSRoleARN)
+	}
+
+	return aws.NewCredentialsCache(provider), nil
+}
+
+// awsMSKIAMAuth retrieves fresh credentials from creds (an
+// aws.CredentialsCache, so this is cheap unless the cached credentials are
+// near expiry) and maps them onto the franz-go SASL/AWS auth type. It's
+// called on every SASL handshake rather than once at startup so long-lived
+// connections keep authenticating with non-expired credentials, including
+// across STS AssumeRole token renewal.
+func awsMSKIAMAuth(ctx context.Context, creds aws.CredentialsProvider) (kaws.Auth, error) {
+	v, err := creds.Retrieve(ctx)
+	if err != nil {
+		return kaws.Auth{}, fmt.Errorf("could not retrieve aws credentials for aws_msk_iam: %w", err)
+	}
+
+	return kaws.Auth{
+		AccessKey:    v.AccessKeyID,
+		SecretKey:    v.SecretAccessKey,
+		SessionToken: v.SessionToken,
+		UserAgent:    clientID,
+	}, nil
+}
@@ -0,0 +1,60 @@
+package exporter
+
+import (
+	"fmt"
+	"time"
+)
+
+// recordOffset appends a new (offset, timestamp) sample to the
+// interpolation table for topic/partition, pruning samples older than
+// MaxOffsets entries so the table doesn't grow unbounded on long-lived
+// processes.
+func (e *Exporter) recordOffset(topic string, partition int32, offset int64, ts time.Time) {
+	key := interpolationKey(topic, partition)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	entry, ok := e.interpolationMap[key]
+	if !ok {
+		entry = &interpolationEntry{}
+		e.interpolationMap[key] = entry
+	}
+
+	entry.offsets = append(entry.offsets, offsetEntry{offset: offset, timestamp: ts})
+	if max := e.kafkaOpts.MaxOffsets; max > 0 && len(entry.offsets) > max {
+		entry.offsets = entry.offsets[len(entry.offsets)-max:]
+	}
+}
+
+// lagInSeconds estimates how many seconds behind the broker's current
+// offset a consumer group's committed offset is, by interpolating the
+// recorded (offset, timestamp) samples for topic/partition.
+func (e *Exporter) lagInSeconds(topic string, partition int32, groupOffset int64) (float64, bool) {
+	key := interpolationKey(topic, partition)
+
+	e.mu.Lock()
+	entry, ok := e.interpolationMap[key]
+	var samples []offsetEntry
+	if ok {
+		samples = append(samples, entry.offsets...)
+	}
+	e.mu.Unlock()
+	if !ok || len(samples) < 2 {
+		return 0, false
+	}
+
+	for i := len(samples) - 1; i > 0; i-- {
+		lo, hi := samples[i-1], samples[i]
+		if groupOffset >= lo.offset && groupOffset <= hi.offset && hi.offset != lo.offset {
+			frac := float64(groupOffset-lo.offset) / float64(hi.offset-lo.offset)
+			interpolated := lo.timestamp.Add(time.Duration(frac * float64(hi.timestamp.Sub(lo.timestamp))))
+			return time.Since(interpolated).Seconds(), true
+		}
+	}
+	return 0, false
+}
+
+func interpolationKey(topic string, partition int32) string {
+	return fmt.Sprintf("%s/%d", topic, partition)
+}
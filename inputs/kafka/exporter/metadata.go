@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/log/level"
+	"github.com/twmb/franz-go/pkg/kadm"
+)
+
+// topicsAndPartitions returns the cluster's topic/partition list, served
+// from cache when the last refresh happened within metadataMinInterval.
+// This ports the coalescing pattern TiFlow's kafkaTopicManager uses to
+// avoid hammering the cluster with Metadata RPCs: on large clusters a
+// naive per-scrape client.Topics()/client.Partitions() pair can itself
+// become the dominant source of broker load.
+func (e *Exporter) topicsAndPartitions() ([]string, map[string][]int32, error) {
+	e.metadataMu.Lock()
+	defer e.metadataMu.Unlock()
+
+	if !e.metadataRefreshedAt.IsZero() && time.Since(e.metadataRefreshedAt) < e.metadataMinInterval {
+		return e.cachedTopics, e.cachedPartitions, nil
+	}
+
+	return e.refreshMetadataLocked()
+}
+
+// refreshMetadataLocked forces a Metadata RPC and repopulates the cache.
+// Callers must hold metadataMu.
+func (e *Exporter) refreshMetadataLocked() ([]string, map[string][]int32, error) {
+	if err := e.client.RefreshMetadata(); err != nil {
+		return nil, nil, err
+	}
+
+	topics, err := e.client.Topics()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	partitions := make(map[string][]int32, len(topics))
+	for _, topic := range topics {
+		p, err := e.client.Partitions(topic)
+		if err != nil {
+			level.Error(e.logger).Log("msg", "cannot get partitions of topic during metadata refresh", "topic", topic, "err", err)
+			continue
+		}
+		partitions[topic] = p
+	}
+
+	e.cachedTopics = topics
+	e.cachedPartitions = partitions
+	e.metadataRefreshedAt = time.Now()
+
+	return topics, partitions, nil
+}
+
+// forceMetadataRefresh bypasses metadataMinInterval. Call it when a scrape
+// hits sarama.ErrUnknownTopicOrPartition so newly-created topics become
+// visible without waiting out the full coalescing interval.
+func (e *Exporter) forceMetadataRefresh() ([]string, map[string][]int32, error) {
+	e.metadataMu.Lock()
+	defer e.metadataMu.Unlock()
+	return e.refreshMetadataLocked()
+}
+
+func isUnknownTopicOrPartition(err error) bool {
+	return errors.Is(err, sarama.ErrUnknownTopicOrPartition)
+}
+
+// franzMetadata is collectFranz's equivalent of topicsAndPartitions: it
+// serves the cluster/topic/partition/broker list from cache when the last
+// refresh happened within metadataMinInterval, sharing the same lock,
+// refresh timestamp and coalescing behavior topicsAndPartitions uses for
+// the sarama backend (the metadata itself is cached in cachedFranzMetadata). Per-partition offsets are fetched fresh on every call by the
+// caller (kadm.ListEndOffsets/ListStartOffsets) since those, unlike the
+// topic/partition list, are expected to change every scrape.
+func (e *Exporter) franzMetadata(ctx context.Context) (kadm.Metadata, error) {
+	e.metadataMu.Lock()
+	defer e.metadataMu.Unlock()
+
+	if !e.metadataRefreshedAt.IsZero() && time.Since(e.metadataRefreshedAt) < e.metadataMinInterval {
+		return e.cachedFranzMetadata, nil
+	}
+
+	metadata, err := e.kadmClient.Metadata(ctx)
+	if err != nil {
+		return kadm.Metadata{}, err
+	}
+
+	e.cachedFranzMetadata = metadata
+	e.metadataRefreshedAt = time.Now()
+
+	return metadata, nil
+}
@@ -0,0 +1,115 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// refreshSkew is subtracted from a token's expiry when deciding whether a
+// cached token is still usable, so we never hand the broker a token that
+// is about to expire mid-handshake.
+const refreshSkew = 30 * time.Second
+
+// oauthTokenProvider implements sarama.AccessTokenProvider for SASL/OAUTHBEARER.
+// It fetches a bearer token from an OAuth2 token endpoint using the
+// client_credentials grant, and caches it until it is close to expiry.
+type oauthTokenProvider struct {
+	mu sync.Mutex
+
+	tokenFile  string
+	scopes     []string
+	extensions map[string]string
+	conf       *clientcredentials.Config
+
+	cachedToken  string
+	cachedExpiry time.Time
+}
+
+func newOAuthTokenProvider(opts Options) (*oauthTokenProvider, error) {
+	p := &oauthTokenProvider{
+		tokenFile:  opts.OAuthTokenFile,
+		extensions: opts.OAuthExtensions,
+	}
+
+	if p.tokenFile != "" {
+		return p, nil
+	}
+
+	if opts.OAuthTokenURL == "" || opts.OAuthClientID == "" || opts.OAuthClientSecret == "" {
+		return nil, fmt.Errorf("oauth_token_url, oauth_client_id and oauth_client_secret are required when sasl_mechanism is oauthbearer (or set oauth_token_file)")
+	}
+
+	p.conf = &clientcredentials.Config{
+		ClientID:     opts.OAuthClientID,
+		ClientSecret: opts.OAuthClientSecret,
+		TokenURL:     opts.OAuthTokenURL,
+		Scopes:       opts.OAuthScopes,
+	}
+
+	return p, nil
+}
+
+// Token implements sarama.AccessTokenProvider. It is called by sarama
+// before every SASL handshake, so it must be cheap when the cached token
+// is still valid.
+func (p *oauthTokenProvider) Token() (*sarama.AccessToken, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tokenFile != "" {
+		raw, err := ioutil.ReadFile(p.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read oauth_token_file: %w", err)
+		}
+		return &sarama.AccessToken{Token: strings.TrimSpace(string(raw)), Extensions: p.extensions}, nil
+	}
+
+	if p.cachedToken != "" && time.Now().Before(p.cachedExpiry.Add(-refreshSkew)) {
+		return &sarama.AccessToken{Token: p.cachedToken, Extensions: p.extensions}, nil
+	}
+
+	token, err := p.conf.Token(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch oauth token: %w", err)
+	}
+
+	expiry := token.Expiry
+	if expiry.IsZero() {
+		if claims, err := parseJWTExpiry(token.AccessToken); err == nil {
+			expiry = claims
+		} else {
+			expiry = time.Now().Add(5 * time.Minute)
+		}
+	}
+
+	p.cachedToken = token.AccessToken
+	p.cachedExpiry = expiry
+
+	return &sarama.AccessToken{Token: p.cachedToken, Extensions: p.extensions}, nil
+}
+
+// parseJWTExpiry extracts the `exp` claim from a JWT without validating
+// its signature: we trust the token because we just fetched it directly
+// from the configured token endpoint over TLS.
+func parseJWTExpiry(rawToken string) (time.Time, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser()
+	if _, _, err := parser.ParseUnverified(rawToken, claims); err != nil {
+		return time.Time{}, err
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("token has no exp claim")
+	}
+
+	return time.Unix(int64(exp), 0), nil
+}
@@ -0,0 +1,42 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+func ConfigureSASL(config *sarama.Config, opts Options) error {
+	if !opts.UseSASL {
+		return nil
+	}
+
+	config.Net.SASL.Enable = true
+	config.Net.SASL.Handshake = opts.UseSASLHandshake
+
+	switch strings.ToLower(opts.SaslMechanism) {
+	case "scram-sha512":
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &XDGSCRAMClient{HashGeneratorFcn: SHA512} }
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+	case "scram-sha256":
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &XDGSCRAMClient{HashGeneratorFcn: SHA256} }
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+	case "oauthbearer":
+		provider, err := newOAuthTokenProvider(opts)
+		if err != nil {
+			return fmt.Errorf("could not configure oauthbearer token provider: %w", err)
+		}
+		config.Net.SASL.Mechanism = sarama.SASLTypeOAuth
+		config.Net.SASL.TokenProvider = provider
+	case "plain", "":
+		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	default:
+		return fmt.Errorf("invalid sasl mechanism %q: can only be plain, scram-sha256, scram-sha512 or oauthbearer", opts.SaslMechanism)
+	}
+
+	config.Net.SASL.User = opts.SaslUsername
+	config.Net.SASL.Password = opts.SaslPassword
+
+	return nil
+}
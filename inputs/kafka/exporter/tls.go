@@ -0,0 +1,175 @@
+package exporter
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloadInterval is how often the watcher stats the cert/key files
+// for changes. Short-lived certs issued by cert-manager/Vault are
+// typically rotated on the order of hours, so polling every 30s is cheap
+// and plenty responsive without needing a filesystem notification lib.
+const certReloadInterval = 30 * time.Second
+
+// CertReloader is the stoppable handle ConfigureTLS hands back for a
+// client cert/key pair it's watching for rotation, so a caller can shut
+// down the background reload goroutine once it's done with the config.
+type CertReloader interface {
+	Stop()
+}
+
+// certReloader keeps an in-memory tls.Certificate fresh by re-reading its
+// backing cert/key files whenever their mtimes change, and hands it out
+// via tls.Config.GetClientCertificate so a long-lived sarama connection
+// picks up a rotated keypair without a restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	reloadInterval time.Duration
+
+	mu          sync.RWMutex
+	cert        tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	return newCertReloaderWithInterval(certFile, keyFile, certReloadInterval)
+}
+
+// newCertReloaderWithInterval is newCertReloader with the poll interval
+// broken out so tests can rotate a cert without waiting on the real
+// certReloadInterval.
+func newCertReloaderWithInterval(certFile, keyFile string, interval time.Duration) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, reloadInterval: interval, stopCh: make(chan struct{})}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	go r.watch()
+
+	return r, nil
+}
+
+// Stop ends the background watch goroutine. Safe to call more than once
+// and safe to call even if watch never got a chance to observe stopCh.
+func (r *certReloader) Stop() {
+	r.stopOnce.Do(func() { close(r.stopCh) })
+}
+
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Copy out rather than returning &r.cert: reload() always replaces
+	// r.cert wholesale rather than mutating it in place, so a copy taken
+	// under RLock is a safe, independent snapshot for the caller to keep
+	// using after this function returns and the lock is released.
+	cert := r.cert
+	return &cert, nil
+}
+
+func (r *certReloader) watch() {
+	ticker := time.NewTicker(r.reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		certInfo, err := os.Stat(r.certFile)
+		if err != nil {
+			continue
+		}
+		keyInfo, err := os.Stat(r.keyFile)
+		if err != nil {
+			continue
+		}
+
+		r.mu.RLock()
+		unchanged := certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime)
+		r.mu.RUnlock()
+		if unchanged {
+			continue
+		}
+
+		if err := r.reload(); err != nil {
+			// The new files may be mid-write (cert-manager/Vault write
+			// cert then key non-atomically); keep serving the last good
+			// keypair and pick the new one up on the next tick.
+			continue
+		}
+	}
+}
+
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.cert = cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	r.mu.Unlock()
+
+	return nil
+}
+
+func tlsMinVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid tls_min_version %q: must be one of 1.0, 1.1, 1.2, 1.3", v)
+	}
+}
+
+var cipherSuitesByName = func() map[string]uint16 {
+	m := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		m[s.Name] = s.ID
+	}
+	for _, s := range tls.InsecureCipherSuites() {
+		m[s.Name] = s.ID
+	}
+	return m
+}()
+
+func tlsCipherSuites(names []string) ([]uint16, error) {
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := cipherSuitesByName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tls_cipher_suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -96,6 +96,19 @@ type Instance struct {
 	// Address (host:port) of Kafka server.
 	KafkaURIs []string `toml:"kafka_uris,omitempty"`
 
+	// Client backend used to talk to the cluster: "sarama" (default) or
+	// "franz" (github.com/twmb/franz-go), which additionally supports
+	// SASL/AWS_MSK_IAM and has a lighter connection model.
+	Client string `toml:"client,omitempty"`
+
+	// AWS region/profile/role used to generate AWS_MSK_IAM SigV4 SASL
+	// tokens. Only used when sasl_mechanism is "aws_msk_iam" and
+	// client is "franz".
+	AWSRegion   string `toml:"aws_region,omitempty"`
+	AWSProfile  string `toml:"aws_profile,omitempty"`
+	AWSRoleARN  string `toml:"aws_role_arn,omitempty"`
+	AWSEndpoint string `toml:"aws_endpoint,omitempty"`
+
 	// Connect using SASL/PLAIN
 	UseSASL bool `toml:"use_sasl,omitempty"`
 
@@ -108,9 +121,31 @@ type Instance struct {
 	// SASL user password
 	SASLPassword string `toml:"sasl_password,omitempty"`
 
-	// The SASL SCRAM SHA algorithm sha256 or sha512 as mechanism
+	// The SASL mechanism: plain, scram-sha256, scram-sha512, oauthbearer,
+	// or aws_msk_iam (client = "franz" only)
 	SASLMechanism string `toml:"sasl_mechanism,omitempty"`
 
+	// OAuth2/OIDC token endpoint used to fetch a SASL/OAUTHBEARER token via
+	// the client_credentials grant. Required when sasl_mechanism is
+	// "oauthbearer", unless OAuthTokenFile is set instead.
+	OAuthTokenURL string `toml:"oauth_token_url,omitempty"`
+
+	// OAuth2 client credentials used against OAuthTokenURL
+	OAuthClientID     string `toml:"oauth_client_id,omitempty"`
+	OAuthClientSecret string `toml:"oauth_client_secret,omitempty"`
+
+	// Scopes requested when fetching the OAuth token
+	OAuthScopes []string `toml:"oauth_scopes,omitempty"`
+
+	// Extra SASL extensions sent alongside the OAUTHBEARER token (e.g. for
+	// brokers that require a tenant or cluster identifier)
+	OAuthExtensions map[string]string `toml:"oauth_extensions,omitempty"`
+
+	// Path to a pre-provisioned JWT to use as the bearer token instead of
+	// fetching one from OAuthTokenURL. The file is re-read on every SASL
+	// handshake so the token can be rotated out-of-band.
+	OAuthTokenFile string `toml:"oauth_token_file,omitempty"`
+
 	// Connect using TLS
 	UseTLS bool `toml:"use_tls,omitempty"`
 
@@ -126,6 +161,19 @@ type Instance struct {
 	// If true, the server's certificate will not be checked for validity. This will make your HTTPS connections insecure
 	InsecureSkipVerify bool `toml:"insecure_skip_verify,omitempty"`
 
+	// SNI / hostname used to verify the broker's certificate, when it
+	// differs from the host in kafka_uris (e.g. connecting through a
+	// load balancer or SNI-routed proxy)
+	TLSServerName string `toml:"tls_server_name,omitempty"`
+
+	// Minimum TLS version to negotiate: "1.0", "1.1", "1.2" (default) or "1.3"
+	TLSMinVersion string `toml:"tls_min_version,omitempty"`
+
+	// Cipher suites allowed when negotiating TLS < 1.3, by name (e.g.
+	// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"). Leave empty for Go's
+	// default suite list.
+	TLSCipherSuites []string `toml:"tls_cipher_suites,omitempty"`
+
 	// Kafka broker version
 	KafkaVersion string `toml:"kafka_version,omitempty"`
 
@@ -138,6 +186,13 @@ type Instance struct {
 	// Metadata refresh interval
 	MetadataRefreshInterval string `toml:"metadata_refresh_interval,omitempty"`
 
+	// Minimum time between Metadata RPCs issued to refresh the cached
+	// topic/partition list a scrape is served from. Defaults to 10m.
+	// Gather() serves from cache within this interval instead of hitting
+	// the cluster on every scrape, which matters on clusters with
+	// thousands of topics or many categraf agents polling them.
+	MetadataMinInterval string `toml:"metadata_min_interval,omitempty"`
+
 	// If true, all scrapes will trigger kafka operations otherwise, they will share results. WARN: This should be disabled on large clusters
 	AllowConcurrent *bool `toml:"allow_concurrency,omitempty"`
 
@@ -153,6 +208,32 @@ type Instance struct {
 	// Regex filter for consumer groups to be monitored
 	GroupFilter string `toml:"groups_filter_regex,omitempty"`
 
+	// Regex filter for topics to be excluded, applied after TopicsFilter
+	TopicExcludeFilter string `toml:"topic_exclude_regex,omitempty"`
+
+	// Regex filter for consumer groups to be excluded, applied after GroupFilter
+	GroupExcludeFilter string `toml:"group_exclude_regex,omitempty"`
+
+	// Emit kafka_topic_partitions and kafka_topic_partition_current_offset/
+	// oldest_offset metrics. Defaults to true; set to false on clusters with
+	// thousands of topics to cut cardinality.
+	CollectTopicPartitionMetrics *bool `toml:"collect_topic_partition_metrics,omitempty"`
+
+	// Emit kafka_consumergroup_* metrics (current offset, lag). Defaults to true.
+	CollectConsumerGroupMetrics *bool `toml:"collect_consumer_group_metrics,omitempty"`
+
+	// Emit kafka_topic_partition_leader/replicas/in_sync_replica/under_replicated
+	// broker-topic metrics. Defaults to true.
+	CollectBrokerTopicMetrics *bool `toml:"collect_broker_topic_metrics,omitempty"`
+
+	// How consumer group lag is computed: "interpolation" (default) keeps a
+	// table of recent (offset, timestamp) samples per partition and
+	// estimates lag-in-seconds, "offset" just reports the raw
+	// latest-offset-minus-committed-offset difference. "offset" is cheaper
+	// and should be preferred on clusters where the interpolation table
+	// becomes expensive to maintain.
+	OffsetInterpolation string `toml:"offset_interpolation,omitempty"`
+
 	l klog.Logger        `toml:"-"`
 	e *exporter.Exporter `toml:"-"`
 }
@@ -164,11 +245,23 @@ func (ins *Instance) Init() error {
 	if ins.UseTLS && (ins.CertFile == "" || ins.KeyFile == "") {
 		return fmt.Errorf("tls is enabled but key pair was not provided")
 	}
-	if ins.UseSASL && (ins.SASLPassword == "" || ins.SASLUsername == "") {
+	if ins.UseSASL && strings.ToLower(ins.SASLMechanism) != "oauthbearer" && strings.ToLower(ins.SASLMechanism) != "aws_msk_iam" && (ins.SASLPassword == "" || ins.SASLUsername == "") {
 		return fmt.Errorf("SASL is enabled but username or password was not provided")
 	}
-	if ins.UseZooKeeperLag && (len(ins.ZookeeperURIs) == 0 || ins.ZookeeperURIs[0] == "") {
-		return fmt.Errorf("zookeeper lag is enabled but no zookeeper uri was provided")
+	if ins.UseSASL && strings.ToLower(ins.SASLMechanism) == "oauthbearer" && ins.OAuthTokenFile == "" && (ins.OAuthTokenURL == "" || ins.OAuthClientID == "" || ins.OAuthClientSecret == "") {
+		return fmt.Errorf("sasl_mechanism is oauthbearer but oauth_token_file or oauth_token_url/oauth_client_id/oauth_client_secret were not provided")
+	}
+	if strings.ToLower(ins.SASLMechanism) == "aws_msk_iam" && strings.ToLower(ins.Client) != "franz" {
+		return fmt.Errorf("sasl_mechanism aws_msk_iam requires client = \"franz\"")
+	}
+	if strings.ToLower(ins.SASLMechanism) == "aws_msk_iam" && ins.AWSRegion == "" {
+		return fmt.Errorf("sasl_mechanism is aws_msk_iam but aws_region was not provided")
+	}
+	if ins.UseZooKeeperLag {
+		// collectConsumerGroups has no ZooKeeper-backed lag path, so enabling
+		// use_zookeeper_lag would silently collect nothing. Reject it here
+		// rather than shipping a no-op knob.
+		return fmt.Errorf("use_zookeeper_lag is not implemented yet")
 	}
 
 	// default options
@@ -182,6 +275,17 @@ func (ins *Instance) Init() error {
 	if len(ins.MetadataRefreshInterval) == 0 {
 		ins.MetadataRefreshInterval = "1s"
 	}
+	if len(ins.MetadataMinInterval) == 0 {
+		ins.MetadataMinInterval = "10m"
+	}
+	if len(ins.Client) == 0 {
+		ins.Client = "sarama"
+	}
+	switch strings.ToLower(ins.Client) {
+	case "sarama", "franz":
+	default:
+		return fmt.Errorf("client must be \"sarama\" or \"franz\", got %q", ins.Client)
+	}
 	if ins.AllowConcurrent == nil {
 		flag := true
 		ins.AllowConcurrent = &flag
@@ -198,26 +302,66 @@ func (ins *Instance) Init() error {
 	if len(ins.GroupFilter) == 0 {
 		ins.GroupFilter = ".*"
 	}
+	if ins.CollectTopicPartitionMetrics == nil {
+		flag := true
+		ins.CollectTopicPartitionMetrics = &flag
+	}
+	if ins.CollectConsumerGroupMetrics == nil {
+		flag := true
+		ins.CollectConsumerGroupMetrics = &flag
+	}
+	if ins.CollectBrokerTopicMetrics == nil {
+		flag := true
+		ins.CollectBrokerTopicMetrics = &flag
+	}
+	switch ins.OffsetInterpolation {
+	case "":
+		ins.OffsetInterpolation = "interpolation"
+	case "interpolation", "offset":
+	default:
+		return fmt.Errorf("offset_interpolation must be one of \"interpolation\" or \"offset\", got %q", ins.OffsetInterpolation)
+	}
 
 	options := exporter.Options{
-		Uri:                      ins.KafkaURIs,
-		UseSASL:                  ins.UseSASL,
-		UseSASLHandshake:         *ins.UseSASLHandshake,
-		SaslUsername:             ins.SASLUsername,
-		SaslPassword:             string(ins.SASLPassword),
-		SaslMechanism:            ins.SASLMechanism,
-		UseTLS:                   ins.UseTLS,
-		TlsCAFile:                ins.CAFile,
-		TlsCertFile:              ins.CertFile,
-		TlsKeyFile:               ins.KeyFile,
-		TlsInsecureSkipTLSVerify: ins.InsecureSkipVerify,
-		KafkaVersion:             ins.KafkaVersion,
-		UseZooKeeperLag:          ins.UseZooKeeperLag,
-		UriZookeeper:             ins.ZookeeperURIs,
-		MetadataRefreshInterval:  ins.MetadataRefreshInterval,
-		AllowConcurrent:          *ins.AllowConcurrent,
-		MaxOffsets:               ins.MaxOffsets,
-		PruneIntervalSeconds:     ins.PruneIntervalSeconds,
+		Uri:                          ins.KafkaURIs,
+		UseSASL:                      ins.UseSASL,
+		UseSASLHandshake:             *ins.UseSASLHandshake,
+		SaslUsername:                 ins.SASLUsername,
+		SaslPassword:                 string(ins.SASLPassword),
+		SaslMechanism:                ins.SASLMechanism,
+		UseTLS:                       ins.UseTLS,
+		TlsCAFile:                    ins.CAFile,
+		TlsCertFile:                  ins.CertFile,
+		TlsKeyFile:                   ins.KeyFile,
+		TlsInsecureSkipTLSVerify:     ins.InsecureSkipVerify,
+		TlsServerName:                ins.TLSServerName,
+		TlsMinVersion:                ins.TLSMinVersion,
+		TlsCipherSuites:              ins.TLSCipherSuites,
+		KafkaVersion:                 ins.KafkaVersion,
+		UseZooKeeperLag:              ins.UseZooKeeperLag,
+		UriZookeeper:                 ins.ZookeeperURIs,
+		MetadataRefreshInterval:      ins.MetadataRefreshInterval,
+		MetadataMinInterval:          ins.MetadataMinInterval,
+		AllowConcurrent:              *ins.AllowConcurrent,
+		MaxOffsets:                   ins.MaxOffsets,
+		PruneIntervalSeconds:         ins.PruneIntervalSeconds,
+		OAuthTokenURL:                ins.OAuthTokenURL,
+		OAuthClientID:                ins.OAuthClientID,
+		OAuthClientSecret:            ins.OAuthClientSecret,
+		OAuthScopes:                  ins.OAuthScopes,
+		OAuthExtensions:              ins.OAuthExtensions,
+		OAuthTokenFile:               ins.OAuthTokenFile,
+		TopicExcludeFilter:           ins.TopicExcludeFilter,
+		GroupExcludeFilter:           ins.GroupExcludeFilter,
+		CollectTopicPartitionMetrics: *ins.CollectTopicPartitionMetrics,
+		CollectConsumerGroupMetrics:  *ins.CollectConsumerGroupMetrics,
+		CollectBrokerTopicMetrics:    *ins.CollectBrokerTopicMetrics,
+		OffsetInterpolation:          ins.OffsetInterpolation,
+		Client:                       strings.ToLower(ins.Client),
+		AWSRegion:                    ins.AWSRegion,
+		AWSProfile:                   ins.AWSProfile,
+		AWSRoleARN:                   ins.AWSRoleARN,
+		AWSEndpoint:                  ins.AWSEndpoint,
 	}
 
 	encLabels := []string{}
@@ -0,0 +1,139 @@
+package kafka_consumer
+
+import (
+	"sync"
+
+	"flashcat.cloud/categraf/types"
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/log/level"
+	"github.com/toolkits/pkg/container/list"
+)
+
+// consumerHandler implements sarama.ConsumerGroupHandler. It decodes each
+// record via Instance.decodeFn and forwards the result into slist
+// (metrics) and/or the instance's configured logger at info level (log
+// lines), marking the record's offset only once forwarding succeeds. Up to
+// Instance.MaxInFlight records per partition are decoded/forwarded
+// concurrently, but
+// offsets are committed strictly in order (see markCompleted) so a crash
+// never loses a record that was never actually forwarded.
+type consumerHandler struct {
+	ins   *Instance
+	slist *list.SafeList
+}
+
+func (h *consumerHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (h *consumerHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim is invoked once per partition assigned to this consumer, in
+// its own goroutine, so the per-partition ordering state below (nextOffset
+// and completed) is never shared across partitions.
+func (h *consumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	// Bounds how many records from this partition are being
+	// decoded/forwarded concurrently: after taking a message off
+	// claim.Messages() the loop waits for a free slot before reading the
+	// next one, so a slow downstream backpressures the fetch loop instead
+	// of this goroutine buffering unboundedly.
+	sem := make(chan struct{}, h.ins.MaxInFlight)
+	var wg sync.WaitGroup
+
+	var markMu sync.Mutex
+	nextOffset := int64(-1)
+	completed := make(map[int64]struct{})
+
+	// markCompleted records that offset finished forwarding, then commits
+	// every contiguous offset starting at nextOffset that has now
+	// completed. sarama only ever moves a partition's committed offset
+	// forward (it can't un-commit), so marking offset N+1 before offset N
+	// has completed would permanently drop N if the process crashed or was
+	// rebalanced in between. Holding back the mark until the run is
+	// unbroken trades that data loss for, at worst, a later offset being
+	// forwarded again after a crash -- which an at-least-once consumer
+	// already has to tolerate.
+	markCompleted := func(offset int64) {
+		markMu.Lock()
+		defer markMu.Unlock()
+
+		completed[offset] = struct{}{}
+		for {
+			if _, ok := completed[nextOffset]; !ok {
+				return
+			}
+			delete(completed, nextOffset)
+			session.MarkOffset(claim.Topic(), claim.Partition(), nextOffset+1, "")
+			nextOffset++
+		}
+	}
+
+	ctx := session.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				wg.Wait()
+				return nil
+			}
+
+			markMu.Lock()
+			if nextOffset == -1 {
+				nextOffset = msg.Offset
+			}
+			markMu.Unlock()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				wg.Wait()
+				return nil
+			}
+
+			wg.Add(1)
+			go func(msg *sarama.ConsumerMessage) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				if err := h.forward(msg); err != nil {
+					level.Error(h.ins.l).Log("msg", "failed to forward record", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "err", err)
+					return
+				}
+				markCompleted(msg.Offset)
+			}(msg)
+		}
+	}
+}
+
+func (h *consumerHandler) forward(msg *sarama.ConsumerMessage) error {
+	labels := make(map[string]string, len(h.ins.Labels)+len(msg.Headers)+2)
+	for k, v := range h.ins.Labels {
+		labels[k] = v
+	}
+	labels["topic"] = msg.Topic
+	for _, hdr := range msg.Headers {
+		labels[string(hdr.Key)] = string(hdr.Value)
+	}
+
+	samples, lines, err := h.ins.decodeFn(msg.Value, labels)
+	if err != nil {
+		return err
+	}
+
+	if len(samples) > 0 {
+		h.slist.PushFrontBatch(toInterfaceSlice(samples))
+	}
+	for _, line := range lines {
+		level.Info(h.ins.l).Log("topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "line", line)
+	}
+
+	return nil
+}
+
+func toInterfaceSlice(samples []*types.Sample) []interface{} {
+	out := make([]interface{}, len(samples))
+	for i, s := range samples {
+		out[i] = s
+	}
+	return out
+}
@@ -0,0 +1,300 @@
+package kafka_consumer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"flashcat.cloud/categraf/config"
+	"flashcat.cloud/categraf/inputs"
+	"flashcat.cloud/categraf/inputs/kafka/exporter"
+	"flashcat.cloud/categraf/types"
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/log/level"
+	"github.com/toolkits/pkg/container/list"
+
+	klog "github.com/go-kit/log"
+)
+
+const inputName = "kafka_consumer"
+
+// KafkaConsumer subscribes to Kafka topics as a consumer group and turns
+// each record into either metric samples (fed into the SafeList like any
+// other input) or log lines, depending on Instance.Decoder. Log lines are
+// written at info level to the instance's own logger on stderr; they are
+// not sent to categraf's logs pipeline.
+type KafkaConsumer struct {
+	config.Interval
+	Instances []*Instance `toml:"instances"`
+}
+
+func init() {
+	inputs.Add(inputName, func() inputs.Input {
+		return &KafkaConsumer{}
+	})
+}
+
+func (r *KafkaConsumer) Prefix() string { return "" }
+
+func (r *KafkaConsumer) Init() error {
+	if len(r.Instances) == 0 {
+		return types.ErrInstancesEmpty
+	}
+
+	for i := 0; i < len(r.Instances); i++ {
+		if err := r.Instances[i].Init(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *KafkaConsumer) Drop() {
+	for _, ins := range r.Instances {
+		if ins != nil {
+			ins.Stop()
+		}
+	}
+}
+
+// Gather starts each instance's consumer group loop on first call and
+// simply lets it run in the background thereafter: unlike a scrape-style
+// input there is no single point-in-time value to collect, records are
+// forwarded to slist/logs as they arrive.
+func (r *KafkaConsumer) Gather(slist *list.SafeList) {
+	for _, ins := range r.Instances {
+		ins.startOnce(slist)
+	}
+}
+
+// Instance is a single consumer-group subscription.
+type Instance struct {
+	Labels   map[string]string `toml:"labels"`
+	LogLevel string            `toml:"log_level"`
+
+	KafkaURIs []string `toml:"kafka_uris,omitempty"`
+	Topics    []string `toml:"topics"`
+	GroupID   string   `toml:"group_id"`
+
+	// Where to start reading when a partition has no committed offset yet:
+	// "oldest" or "newest" (default).
+	StartOffset string `toml:"start_offset,omitempty"`
+
+	// How to turn a record's value into metrics or log lines: "json" or
+	// "raw". Defaults to "raw". ("influx" and "prometheus_remote_write"
+	// are not implemented yet and are rejected here rather than accepted
+	// and failed on every record.)
+	Decoder string `toml:"decoder,omitempty"`
+
+	// Maximum number of records being decoded/forwarded at once, per
+	// assigned partition. Bounds
+	// memory use under a slow downstream and backpressures the consumer
+	// fetch loop instead of buffering unboundedly. Defaults to 256.
+	MaxInFlight int `toml:"max_in_flight,omitempty"`
+
+	// Reuse of inputs/kafka's auth config so users configure SASL/TLS once
+	// per cluster regardless of which Kafka input they use against it.
+	UseSASL            bool              `toml:"use_sasl,omitempty"`
+	UseSASLHandshake   *bool             `toml:"use_sasl_handshake,omitempty"`
+	SASLUsername       string            `toml:"sasl_username,omitempty"`
+	SASLPassword       string            `toml:"sasl_password,omitempty"`
+	SASLMechanism      string            `toml:"sasl_mechanism,omitempty"`
+	OAuthTokenURL      string            `toml:"oauth_token_url,omitempty"`
+	OAuthClientID      string            `toml:"oauth_client_id,omitempty"`
+	OAuthClientSecret  string            `toml:"oauth_client_secret,omitempty"`
+	OAuthScopes        []string          `toml:"oauth_scopes,omitempty"`
+	OAuthExtensions    map[string]string `toml:"oauth_extensions,omitempty"`
+	OAuthTokenFile     string            `toml:"oauth_token_file,omitempty"`
+	UseTLS             bool              `toml:"use_tls,omitempty"`
+	CAFile             string            `toml:"ca_file,omitempty"`
+	CertFile           string            `toml:"cert_file,omitempty"`
+	KeyFile            string            `toml:"key_file,omitempty"`
+	InsecureSkipVerify bool              `toml:"insecure_skip_verify,omitempty"`
+	TLSServerName      string            `toml:"tls_server_name,omitempty"`
+	TLSMinVersion      string            `toml:"tls_min_version,omitempty"`
+	TLSCipherSuites    []string          `toml:"tls_cipher_suites,omitempty"`
+	KafkaVersion       string            `toml:"kafka_version,omitempty"`
+
+	l        klog.Logger `toml:"-"`
+	decodeFn decodeFunc  `toml:"-"`
+	once     sync.Once   `toml:"-"`
+
+	mu           sync.Mutex            `toml:"-"`
+	cancel       context.CancelFunc    `toml:"-"`
+	certReloader exporter.CertReloader `toml:"-"`
+}
+
+func (ins *Instance) Init() error {
+	if len(ins.KafkaURIs) == 0 || ins.KafkaURIs[0] == "" {
+		return fmt.Errorf("kafka_uris must be specified")
+	}
+	if len(ins.Topics) == 0 {
+		return fmt.Errorf("topics must be specified")
+	}
+	if ins.GroupID == "" {
+		return fmt.Errorf("group_id must be specified")
+	}
+	if ins.UseTLS && (ins.CertFile == "" || ins.KeyFile == "") {
+		return fmt.Errorf("tls is enabled but key pair was not provided")
+	}
+	if ins.UseSASL && strings.ToLower(ins.SASLMechanism) != "oauthbearer" && (ins.SASLUsername == "" || ins.SASLPassword == "") {
+		return fmt.Errorf("SASL is enabled but username or password was not provided")
+	}
+
+	if ins.StartOffset == "" {
+		ins.StartOffset = "newest"
+	}
+	if ins.StartOffset != "oldest" && ins.StartOffset != "newest" {
+		return fmt.Errorf("start_offset must be \"oldest\" or \"newest\", got %q", ins.StartOffset)
+	}
+	if ins.Decoder == "" {
+		ins.Decoder = "raw"
+	}
+	decodeFn, ok := decoders[ins.Decoder]
+	if !ok {
+		return fmt.Errorf("decoder must be one of json, raw, got %q", ins.Decoder)
+	}
+	ins.decodeFn = decodeFn
+	if ins.MaxInFlight <= 0 {
+		ins.MaxInFlight = 256
+	}
+	if ins.UseSASLHandshake == nil {
+		flag := true
+		ins.UseSASLHandshake = &flag
+	}
+	if ins.KafkaVersion == "" {
+		ins.KafkaVersion = sarama.V2_0_0_0.String()
+	}
+
+	ins.l = level.NewFilter(klog.NewLogfmtLogger(klog.NewSyncWriter(os.Stderr)), levelFilter(ins.LogLevel))
+
+	return nil
+}
+
+func (ins *Instance) saramaConfig() (*sarama.Config, error) {
+	config := sarama.NewConfig()
+	config.ClientID = "categraf"
+
+	version, err := sarama.ParseKafkaVersion(ins.KafkaVersion)
+	if err != nil {
+		return nil, err
+	}
+	config.Version = version
+
+	if ins.StartOffset == "oldest" {
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	} else {
+		config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	}
+
+	opts := exporter.Options{
+		UseSASL:                  ins.UseSASL,
+		UseSASLHandshake:         *ins.UseSASLHandshake,
+		SaslUsername:             ins.SASLUsername,
+		SaslPassword:             ins.SASLPassword,
+		SaslMechanism:            ins.SASLMechanism,
+		OAuthTokenURL:            ins.OAuthTokenURL,
+		OAuthClientID:            ins.OAuthClientID,
+		OAuthClientSecret:        ins.OAuthClientSecret,
+		OAuthScopes:              ins.OAuthScopes,
+		OAuthExtensions:          ins.OAuthExtensions,
+		OAuthTokenFile:           ins.OAuthTokenFile,
+		UseTLS:                   ins.UseTLS,
+		TlsCAFile:                ins.CAFile,
+		TlsCertFile:              ins.CertFile,
+		TlsKeyFile:               ins.KeyFile,
+		TlsInsecureSkipTLSVerify: ins.InsecureSkipVerify,
+		TlsServerName:            ins.TLSServerName,
+		TlsMinVersion:            ins.TLSMinVersion,
+		TlsCipherSuites:          ins.TLSCipherSuites,
+	}
+
+	if err := exporter.ConfigureSASL(config, opts); err != nil {
+		return nil, err
+	}
+	if ins.UseTLS {
+		reloader, err := exporter.ConfigureTLS(config, opts)
+		if err != nil {
+			return nil, err
+		}
+		ins.mu.Lock()
+		ins.certReloader = reloader
+		ins.mu.Unlock()
+	}
+
+	return config, nil
+}
+
+// startOnce spins up the consumer group loop the first time Gather is
+// called; subsequent calls are no-ops since the loop runs for the
+// lifetime of the process, not per-scrape.
+func (ins *Instance) startOnce(slist *list.SafeList) {
+	ins.once.Do(func() {
+		cfg, err := ins.saramaConfig()
+		if err != nil {
+			log.Println("E! kafka_consumer: failed to build sarama config:", err)
+			return
+		}
+
+		group, err := sarama.NewConsumerGroup(ins.KafkaURIs, ins.GroupID, cfg)
+		if err != nil {
+			log.Println("E! kafka_consumer: failed to create consumer group:", err)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ins.mu.Lock()
+		ins.cancel = cancel
+		ins.mu.Unlock()
+
+		handler := &consumerHandler{ins: ins, slist: slist}
+
+		go func() {
+			defer group.Close()
+			for {
+				if err := group.Consume(ctx, ins.Topics, handler); err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					level.Error(ins.l).Log("msg", "consume error", "err", err)
+				}
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		}()
+	})
+}
+
+func (ins *Instance) Stop() {
+	ins.mu.Lock()
+	cancel := ins.cancel
+	reloader := ins.certReloader
+	ins.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	if reloader != nil {
+		reloader.Stop()
+	}
+}
+
+func levelFilter(l string) level.Option {
+	switch l {
+	case "debug":
+		return level.AllowDebug()
+	case "info":
+		return level.AllowInfo()
+	case "warn":
+		return level.AllowWarn()
+	case "error":
+		return level.AllowError()
+	default:
+		return level.AllowAll()
+	}
+}